@@ -11,8 +11,11 @@ import (
 	"write-tracer/internal/api"
 	"write-tracer/internal/config"
 	"write-tracer/internal/ebpf"
+	"write-tracer/internal/grpcapi"
 	"write-tracer/internal/output"
 	"write-tracer/internal/pidmgr"
+	"write-tracer/internal/stream"
+	"write-tracer/internal/symbolize"
 )
 
 func main() {
@@ -34,31 +37,59 @@ func main() {
 		slog.Info("Monitoring write calls", "pid", cfg.TargetPID, "file_descriptors", "all")
 	}
 
-	coll, links, err := ebpf.Load(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	coll, links, cgroupInfo, err := ebpf.Load(ctx, cfg)
 	if err != nil {
 		slog.Error("Failed to load eBPF", "error", err)
 		os.Exit(1)
 	}
-	defer coll.Close()
-	for _, l := range links {
-		defer l.Close()
-	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	hub := stream.NewHub()
+	var processor *ebpf.Processor
+	var grpcServer *grpcapi.Server
+
+	// shutdownDone is closed once the signal goroutine's cleanup below has
+	// fully returned, so main blocks past <-ctx.Done() until the drain/flush
+	// actually finishes instead of exiting out from under it: cancel() alone
+	// only unblocks main, it doesn't wait for anything.
+	shutdownDone := make(chan struct{})
 
 	go func() {
+		defer close(shutdownDone)
+
 		c := make(chan os.Signal, 1)
 		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 		<-c
-		slog.Info("Interrupt received")
+		slog.Info("Interrupt received, shutting down")
 		cancel()
+
+		if grpcServer != nil {
+			grpcServer.Stop()
+		}
+
+		if processor != nil {
+			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+			defer shutdownCancel()
+			if err := processor.Shutdown(shutdownCtx); err != nil {
+				slog.Warn("Shutdown did not complete cleanly", "error", err)
+			}
+		} else {
+			coll.Close()
+			for _, l := range links {
+				l.Close()
+			}
+		}
 	}()
 
 	// Initialize PID registry for dynamic tracking
 	registry := pidmgr.New(coll.Maps["tracked_pids"], 5*time.Second)
 	registry.StartLivenessMonitor(ctx)
 
+	symbolizer := symbolize.New()
+	registry.AddExitHook(symbolizer.Invalidate)
+
 	// If a CLI PID was provided, register it in the registry (so liveness monitoring works)
 	if cfg.TargetPID != 0 {
 		if _, err := registry.RegisterPID(cfg.TargetPID); err != nil {
@@ -71,7 +102,7 @@ func main() {
 	}
 
 	if cfg.RESTPort > 0 {
-		server := api.New(registry, cfg.RESTPort)
+		server := api.New(registry, hub, cfg.RESTPort)
 		if err := server.Start(); err != nil {
 			slog.Error("Failed to start REST server", "error", err)
 		} else {
@@ -79,10 +110,18 @@ func main() {
 		}
 	}
 
-	// Update processor to use registry methods if needed, or just let it run.
-	// The processor mainly consumes events. The liveness monitor runs separately.
+	if cfg.GRPCAddr != "" {
+		grpcServer = grpcapi.New(registry, hub, cfg.GRPCAddr)
+		if err := grpcServer.Start(); err != nil {
+			slog.Error("Failed to start gRPC server", "error", err)
+			grpcServer = nil
+		} else {
+			slog.Info("gRPC API server started", "addr", cfg.GRPCAddr)
+		}
+	}
 
-	if err := ebpf.StartProcessing(ctx, cfg, coll.Maps["events"], coll.Maps["tracked_pids"]); err != nil {
+	processor, err = ebpf.StartProcessing(ctx, cfg, coll, links, cgroupInfo, hub, symbolizer, registry)
+	if err != nil {
 		slog.Error("Failed to start processing", "error", err)
 		os.Exit(1)
 	}
@@ -90,4 +129,5 @@ func main() {
 	slog.Info("Tracing write calls... Hit Ctrl-C to stop.")
 	<-ctx.Done()
 	slog.Info("Shutting down...")
+	<-shutdownDone
 }
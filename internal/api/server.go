@@ -10,14 +10,26 @@ import (
 	"strings"
 
 	"write-tracer/internal/pidmgr"
+	"write-tracer/internal/stream"
+
+	"github.com/gorilla/websocket"
 )
 
-// Server provides REST endpoints for managing tracked PIDs.
+// Server provides REST endpoints for managing tracked PIDs, plus WebSocket
+// endpoints for tailing live write events.
 type Server struct {
 	registry *pidmgr.PIDRegistry
+	hub      *stream.Hub
 	addr     string
 }
 
+// upgrader upgrades event-streaming endpoints to WebSocket connections. The
+// server binds to localhost only (see New), so every caller is already
+// trusted and origin checking would add nothing.
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
 // RegisterRequest is the JSON payload for registering a PID.
 type RegisterRequest struct {
 	PID uint32 `json:"pid"`
@@ -50,9 +62,10 @@ type ErrorResponse struct {
 
 // New creates a new API server bound to the given port.
 // It binds to localhost only for security.
-func New(registry *pidmgr.PIDRegistry, port int) *Server {
+func New(registry *pidmgr.PIDRegistry, hub *stream.Hub, port int) *Server {
 	return &Server{
 		registry: registry,
+		hub:      hub,
 		addr:     fmt.Sprintf("127.0.0.1:%d", port),
 	}
 }
@@ -62,6 +75,7 @@ func (s *Server) Start() error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/pids", s.handlePids)
 	mux.HandleFunc("/pids/", s.handlePidByID)
+	mux.HandleFunc("/events", s.handleGlobalEvents)
 
 	go func() {
 		slog.Info("REST API server starting", "addr", s.addr)
@@ -90,19 +104,26 @@ func (s *Server) handlePids(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handlePidByID(w http.ResponseWriter, r *http.Request) {
-	// Extract PID from URL path: /pids/12345
+	// Extract PID from URL path: /pids/12345 or /pids/12345/events
 	path := strings.TrimPrefix(r.URL.Path, "/pids/")
 	if path == "" {
 		s.writeError(w, http.StatusBadRequest, "PID required in URL path")
 		return
 	}
 
-	pid, err := strconv.ParseUint(path, 10, 32)
+	pidStr, eventsEndpoint := strings.CutSuffix(path, "/events")
+
+	pid, err := strconv.ParseUint(pidStr, 10, 32)
 	if err != nil {
 		s.writeError(w, http.StatusBadRequest, "Invalid PID format")
 		return
 	}
 
+	if eventsEndpoint {
+		s.handlePIDEvents(w, r, uint32(pid))
+		return
+	}
+
 	switch r.Method {
 	case http.MethodDelete:
 		s.unregisterPid(w, uint32(pid))
@@ -113,6 +134,64 @@ func (s *Server) handlePidByID(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleGlobalEvents upgrades /events to a WebSocket streaming every write
+// event captured across every tracked PID.
+func (s *Server) handleGlobalEvents(w http.ResponseWriter, r *http.Request) {
+	s.streamEvents(w, r, 0)
+}
+
+// handlePIDEvents upgrades /pids/{pid}/events to a WebSocket streaming only
+// the write events captured for pid.
+func (s *Server) handlePIDEvents(w http.ResponseWriter, r *http.Request, pid uint32) {
+	s.streamEvents(w, r, pid)
+}
+
+// streamEvents upgrades the connection, subscribes to topic pid (0 for the
+// global topic), and forwards every event it receives as a JSON message
+// until the client disconnects or the connection errors.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request, pid uint32) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.Warn("WebSocket upgrade failed", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	sub := s.hub.Subscribe(pid)
+	defer s.hub.Unsubscribe(sub)
+
+	// Drain client-initiated messages (pings, close frames) so the
+	// connection's read side stays serviced; we don't expect real payloads.
+	// disconnected is closed as soon as that read fails, so an idle
+	// subscription (no events to notice the hang-up via a failed WriteJSON)
+	// still gets unsubscribed promptly instead of leaking until the next
+	// event happens to arrive.
+	disconnected := make(chan struct{})
+	go func() {
+		defer close(disconnected)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case ev, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if err := conn.WriteJSON(ev); err != nil {
+				return
+			}
+		case <-disconnected:
+			return
+		}
+	}
+}
+
 func (s *Server) listPids(w http.ResponseWriter, _ *http.Request) {
 	procs := s.registry.List()
 
@@ -27,6 +27,18 @@ type Config struct {
 	MetricsPort          int
 	RESTPort             int
 	SilenceStdout        bool
+	CgroupPath           string
+	ContainerID          string
+	Sinks                []string
+	OTLPEndpoint         string
+	ShutdownTimeout      time.Duration
+	SampleMode           string
+	SampleRate           int
+	SampleWindow         time.Duration
+	SampleRPS            float64
+	SampleBurst          int
+	SamplePerPID         bool
+	GRPCAddr             string
 }
 
 func Parse() Config {
@@ -58,6 +70,23 @@ func Parse() Config {
 	silenceStdoutPtr := flag.Bool("no-stdout", false, "Deactivate logging to stdout")
 	silenceStdoutShorthandPtr := flag.Bool("q", false, "Shorthand for --no-stdout")
 
+	cgroupPtr := flag.String("cgroup", "", "Cgroup path to monitor (traces every process in the cgroup)")
+	containerPtr := flag.String("container", "", "Container ID to monitor (resolved to its cgroup)")
+
+	sinkPtr := flag.String("sink", "stdout,file,loki", "Comma-separated list of output sinks to enable (stdout,file,loki,otlp)")
+	otlpEndpointPtr := flag.String("otlp-endpoint", "", "URL of an OTLP/HTTP logs collector endpoint (required if otlp is in --sink)")
+
+	shutdownTimeoutPtr := flag.Int("shutdown-timeout", 10, "Seconds to wait for buffered events to drain and flush on shutdown")
+
+	sampleModePtr := flag.String("sample-mode", "none", "Sampling mode under high throughput: none, fixed-rate, token-bucket, reservoir")
+	sampleRatePtr := flag.Int("sample-rate", 1, "fixed-rate mode: keep 1 of every N events per (pid,fd); reservoir mode: reservoir size K per window")
+	sampleWindowPtr := flag.Int("sample-window", 1, "reservoir mode: window size in seconds")
+	sampleRPSPtr := flag.Float64("sample-rps", 0, "token-bucket mode: events/sec limit, global unless --sample-per-pid is set")
+	sampleBurstPtr := flag.Int("sample-burst", 0, "token-bucket mode: burst size (defaults to sample-rps)")
+	samplePerPIDPtr := flag.Bool("sample-per-pid", false, "token-bucket mode: apply the rate limit per PID instead of globally")
+
+	grpcAddrPtr := flag.String("grpc-addr", "", "Address for the gRPC API to listen on, e.g. :9090 (empty disables it)")
+
 	flag.Usage = func() {
 		fmt.Printf("Usage: %s --pid <pid> [options]\n\n", os.Args[0])
 		fmt.Println("Options:")
@@ -69,9 +98,9 @@ func Parse() Config {
 	targetPID := coalesce(*pidShorthandPtr, *pidPtr)
 	restPort := coalesce(*restPortShorthandPtr, *restPortPtr)
 
-	// PID is optional if REST mode is enabled (REST can register PIDs dynamically)
-	if targetPID == 0 && restPort == 0 {
-		slog.Error("PID is required (or enable REST API with --rest-port)")
+	// PID is optional if REST mode, a cgroup, or a container is given instead
+	if targetPID == 0 && restPort == 0 && *cgroupPtr == "" && *containerPtr == "" {
+		slog.Error("PID is required (or use --rest-port, --cgroup, or --container)")
 		flag.Usage()
 		os.Exit(1)
 	}
@@ -87,6 +116,17 @@ func Parse() Config {
 	if maxRecords == 0 {
 		maxRecords = 1000
 	}
+	shutdownTimeout := *shutdownTimeoutPtr
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 10
+	}
+
+	var sinks []string
+	for _, s := range strings.Split(*sinkPtr, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			sinks = append(sinks, s)
+		}
+	}
 
 	cfg := Config{
 		TargetPID:            uint32(targetPID),
@@ -97,6 +137,18 @@ func Parse() Config {
 		MetricsPort:          *metricsPortPtr,
 		RESTPort:             restPort,
 		SilenceStdout:        *silenceStdoutPtr || *silenceStdoutShorthandPtr,
+		CgroupPath:           *cgroupPtr,
+		ContainerID:          *containerPtr,
+		Sinks:                sinks,
+		OTLPEndpoint:         *otlpEndpointPtr,
+		ShutdownTimeout:      time.Duration(shutdownTimeout) * time.Second,
+		SampleMode:           *sampleModePtr,
+		SampleRate:           *sampleRatePtr,
+		SampleWindow:         time.Duration(*sampleWindowPtr) * time.Second,
+		SampleRPS:            *sampleRPSPtr,
+		SampleBurst:          *sampleBurstPtr,
+		SamplePerPID:         *samplePerPIDPtr,
+		GRPCAddr:             *grpcAddrPtr,
 	}
 
 	if fdString != "" {
@@ -0,0 +1,172 @@
+// Package container resolves container IDs (and plain cgroup paths) to the
+// cgroup id the eBPF programs use to scope tracing to every process in a
+// cgroup, rather than a single PID.
+package container
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// cgroupRoot is the standard cgroup v2 mountpoint.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// Info describes the cgroup a traced container or cgroup path belongs to.
+type Info struct {
+	ContainerID string
+	CgroupPath  string
+	CgroupID    uint64
+}
+
+// Resolver maps container IDs to their cgroup information and caches the
+// results, since the cgroup filesystem walk is comparatively expensive.
+type Resolver struct {
+	mu    sync.RWMutex
+	known map[string]Info
+}
+
+// NewResolver creates an empty Resolver.
+func NewResolver() *Resolver {
+	return &Resolver{known: make(map[string]Info)}
+}
+
+// Resolve looks up a container's cgroup information by container ID,
+// searching the cgroup filesystem for a scope/slice directory whose name
+// contains it (the convention used by Docker, Podman, and containerd).
+func (r *Resolver) Resolve(containerID string) (Info, error) {
+	r.mu.RLock()
+	if info, ok := r.known[containerID]; ok {
+		r.mu.RUnlock()
+		return info, nil
+	}
+	r.mu.RUnlock()
+
+	path, err := findCgroupPath(containerID)
+	if err != nil {
+		return Info{}, err
+	}
+
+	info, err := infoForPath(path)
+	if err != nil {
+		return Info{}, err
+	}
+	info.ContainerID = containerID
+
+	r.mu.Lock()
+	r.known[containerID] = info
+	r.mu.Unlock()
+
+	return info, nil
+}
+
+// ResolvePath resolves a cgroup path directly (e.g. one given via
+// --cgroup), without requiring a container ID.
+func ResolvePath(cgroupPath string) (Info, error) {
+	return infoForPath(cgroupPath)
+}
+
+// infoForPath stats a cgroup directory and reports its kernfs id, which is
+// the same id bpf_get_current_cgroup_id() returns in kernel space.
+func infoForPath(path string) (Info, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return Info{}, fmt.Errorf("stat cgroup %s: %w", path, err)
+	}
+	return Info{CgroupPath: path, CgroupID: st.Ino}, nil
+}
+
+func findCgroupPath(containerID string) (string, error) {
+	var found string
+	err := filepath.WalkDir(cgroupRoot, func(path string, d fs.DirEntry, err error) error {
+		if found != "" {
+			return filepath.SkipAll
+		}
+		if err != nil {
+			// Best-effort: cgroup dirs can disappear mid-walk.
+			return nil
+		}
+		if d.IsDir() && strings.Contains(d.Name(), containerID) {
+			found = path
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("walk %s: %w", cgroupRoot, err)
+	}
+	if found == "" {
+		return "", fmt.Errorf("no cgroup found for container %s", containerID)
+	}
+	return found, nil
+}
+
+// Watch watches dir for newly created or renamed-in cgroup directories and
+// invokes onNew with each one's resolved Info. It runs until stop is
+// closed. This lets a tracer pick up sibling containers (e.g. new pods in
+// the same slice) that appear after startup.
+func Watch(dir string, stop <-chan struct{}, onNew func(Info)) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return fmt.Errorf("inotify init: %w", err)
+	}
+
+	if _, err := syscall.InotifyAddWatch(fd, dir, syscall.IN_CREATE|syscall.IN_MOVED_TO); err != nil {
+		syscall.Close(fd)
+		return fmt.Errorf("inotify watch %s: %w", dir, err)
+	}
+
+	var closeOnce sync.Once
+	closeFd := func() { closeOnce.Do(func() { syscall.Close(fd) }) }
+
+	go func() {
+		<-stop
+		closeFd()
+	}()
+
+	go func() {
+		defer closeFd()
+
+		buf := make([]byte, 4096)
+		for {
+			n, err := syscall.Read(fd, buf)
+			if err != nil || n <= 0 {
+				return
+			}
+			// Closing fd doesn't wake a Read already blocked on it, so a
+			// Read can still return after stop; report nothing then.
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+				raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				nameLen := int(raw.Len)
+				name := ""
+				if nameLen > 0 {
+					start := offset + syscall.SizeofInotifyEvent
+					name = strings.TrimRight(string(buf[start:start+nameLen]), "\x00")
+				}
+				offset += syscall.SizeofInotifyEvent + nameLen
+
+				if name == "" {
+					continue
+				}
+
+				info, err := infoForPath(filepath.Join(dir, name))
+				if err != nil {
+					continue
+				}
+				onNew(info)
+			}
+		}
+	}()
+
+	return nil
+}
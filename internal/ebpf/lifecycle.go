@@ -0,0 +1,65 @@
+package ebpf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+
+	"write-tracer/internal/pidmgr"
+
+	"github.com/cilium/ebpf/ringbuf"
+)
+
+// lifecycleEventType distinguishes the two record shapes the
+// trace_sched_process_fork/trace_sched_process_exit programs emit onto the
+// lifecycle_events ring buffer.
+type lifecycleEventType uint32
+
+const (
+	lifecycleFork lifecycleEventType = 0
+	lifecycleExit lifecycleEventType = 1
+)
+
+// kernelLifecycleEvent mirrors the record written by the fork/exit
+// tracepoint programs. For a fork event, ParentTID/TID are the forking
+// thread and its new child; for an exit event, only TID (the thread that
+// exited) is meaningful.
+type kernelLifecycleEvent struct {
+	EventType lifecycleEventType
+	ParentTID uint32
+	TID       uint32
+}
+
+// readLifecycleEvents mirrors fork/exit tracepoint events into registry, so
+// TrackedProcess.ThreadIDs and process unregistration stay in sync with the
+// tracked_pids map without the registry having to poll /proc.
+func readLifecycleEvents(rd *ringbuf.Reader, registry *pidmgr.PIDRegistry, done chan<- struct{}) {
+	defer close(done)
+
+	for {
+		record, err := rd.Read()
+		if err != nil {
+			if errors.Is(err, ringbuf.ErrClosed) {
+				return
+			}
+			slog.Error("Lifecycle ring buffer read failed", "error", err)
+			continue
+		}
+
+		var ev kernelLifecycleEvent
+		if err := binary.Read(bytes.NewReader(record.RawSample), binary.LittleEndian, &ev); err != nil {
+			slog.Error("Lifecycle event parse failed", "error", err)
+			continue
+		}
+
+		switch ev.EventType {
+		case lifecycleFork:
+			registry.HandleFork(ev.ParentTID, ev.TID)
+		case lifecycleExit:
+			registry.HandleExit(ev.TID)
+		default:
+			slog.Warn("Unknown lifecycle event type", "type", ev.EventType)
+		}
+	}
+}
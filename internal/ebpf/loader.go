@@ -1,12 +1,15 @@
 package ebpf
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"strconv"
 
 	"write-tracer/internal/config"
+	"write-tracer/internal/container"
 
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/link"
@@ -15,19 +18,33 @@ import (
 
 //go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags $BPF_CFLAGS bpf ../../bpf/write_tracer.bpf.c -- -I../../bpf/headers
 
-func Load(cfg config.Config) (*ebpf.Collection, []link.Link, error) {
+// Load loads the eBPF collection, configures it from cfg, and attaches its
+// tracepoints. The returned *container.Info is non-nil only when cfg scopes
+// the tracer to a cgroup or container, and should be threaded through to
+// StartProcessing so captured events can be enriched with it. When scoped to
+// a cgroup or container, Load also starts a background watch for sibling
+// cgroups created after startup (e.g. other containers joining the same
+// slice/pod), adding each one to cgroup_ids as it appears; the watch stops
+// when ctx is canceled.
+func Load(ctx context.Context, cfg config.Config) (*ebpf.Collection, []link.Link, *container.Info, error) {
 	if err := rlimit.RemoveMemlock(); err != nil {
-		return nil, nil, fmt.Errorf("remove memlock: %w", err)
+		return nil, nil, nil, fmt.Errorf("remove memlock: %w", err)
 	}
 
 	spec, err := loadBpf()
 	if err != nil {
-		return nil, nil, fmt.Errorf("load spec: %w", err)
+		return nil, nil, nil, fmt.Errorf("load spec: %w", err)
 	}
 
 	coll, err := ebpf.NewCollection(spec)
 	if err != nil {
-		return nil, nil, fmt.Errorf("create collection: %w", err)
+		return nil, nil, nil, fmt.Errorf("create collection: %w", err)
+	}
+
+	cgroupInfo, err := resolveCgroup(cfg)
+	if err != nil {
+		coll.Close()
+		return nil, nil, nil, err
 	}
 
 	bpfCfg := bpfConfig{
@@ -35,9 +52,23 @@ func Load(cfg config.Config) (*ebpf.Collection, []link.Link, error) {
 		NumFds:    cfg.NumFDs,
 		TargetFds: cfg.TargetFDs,
 	}
+	if cgroupInfo != nil {
+		bpfCfg.FilterCgroup = 1
+		bpfCfg.TargetCgroupId = cgroupInfo.CgroupID
+	}
 	if err := coll.Maps["config_map"].Update(uint32(0), bpfCfg, ebpf.UpdateAny); err != nil {
 		coll.Close()
-		return nil, nil, fmt.Errorf("update config map: %w", err)
+		return nil, nil, nil, fmt.Errorf("update config map: %w", err)
+	}
+
+	if cgroupInfo != nil {
+		if err := coll.Maps["cgroup_ids"].Update(cgroupInfo.CgroupID, uint32(1), ebpf.UpdateAny); err != nil {
+			coll.Close()
+			return nil, nil, nil, fmt.Errorf("update cgroup_ids map: %w", err)
+		}
+		slog.Info("Scoped tracing to cgroup", "cgroup_path", cgroupInfo.CgroupPath, "cgroup_id", cgroupInfo.CgroupID)
+
+		watchSiblingCgroups(ctx, coll, cgroupInfo)
 	}
 
 	count := 0
@@ -47,7 +78,7 @@ func Load(cfg config.Config) (*ebpf.Collection, []link.Link, error) {
 		count, err = InitTrackedPids(coll, cfg.TargetPID)
 		if err != nil {
 			coll.Close()
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		slog.Info("Initialized tracking", "target_pid", cfg.TargetPID, "threads_found", count)
 	}
@@ -55,10 +86,58 @@ func Load(cfg config.Config) (*ebpf.Collection, []link.Link, error) {
 	links, err := attachTracepoints(coll)
 	if err != nil {
 		coll.Close()
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+
+	return coll, links, cgroupInfo, nil
+}
+
+// resolveCgroup turns cfg's --cgroup/--container flags into the cgroup
+// info the eBPF program needs, or returns nil if neither was given.
+func resolveCgroup(cfg config.Config) (*container.Info, error) {
+	switch {
+	case cfg.ContainerID != "":
+		info, err := container.NewResolver().Resolve(cfg.ContainerID)
+		if err != nil {
+			return nil, fmt.Errorf("resolve container %s: %w", cfg.ContainerID, err)
+		}
+		return &info, nil
+
+	case cfg.CgroupPath != "":
+		info, err := container.ResolvePath(cfg.CgroupPath)
+		if err != nil {
+			return nil, fmt.Errorf("resolve cgroup %s: %w", cfg.CgroupPath, err)
+		}
+		return &info, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+// watchSiblingCgroups watches the parent directory of cgroupInfo's cgroup
+// for newly created or renamed-in sibling cgroups and adds each one's id to
+// cgroup_ids, so containers that join the same slice/pod after this tracer
+// attached get traced too instead of only the one resolved at startup. The
+// watch runs until ctx is canceled; a failure to start it is logged and
+// non-fatal, since the tracer still works for the cgroup it was given.
+func watchSiblingCgroups(ctx context.Context, coll *ebpf.Collection, cgroupInfo *container.Info) {
+	dir := filepath.Dir(cgroupInfo.CgroupPath)
+
+	onNew := func(info container.Info) {
+		if info.CgroupID == cgroupInfo.CgroupID {
+			return
+		}
+		if err := coll.Maps["cgroup_ids"].Update(info.CgroupID, uint32(1), ebpf.UpdateAny); err != nil {
+			slog.Warn("Failed to track sibling cgroup", "cgroup_path", info.CgroupPath, "error", err)
+			return
+		}
+		slog.Info("Auto-tracking new sibling cgroup", "cgroup_path", info.CgroupPath, "cgroup_id", info.CgroupID)
 	}
 
-	return coll, links, nil
+	if err := container.Watch(dir, ctx.Done(), onNew); err != nil {
+		slog.Warn("Failed to watch for sibling cgroups", "dir", dir, "error", err)
+	}
 }
 
 func InitTrackedPids(coll *ebpf.Collection, targetPID uint32) (int, error) {
@@ -1,69 +1,192 @@
 package ebpf
 
 import (
-	"bytes"
 	"context"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"log/slog"
 	"time"
 
 	"write-tracer/internal/config"
+	"write-tracer/internal/container"
 	"write-tracer/internal/event"
 	"write-tracer/internal/output"
+	"write-tracer/internal/pidmgr"
+	"write-tracer/internal/sampling"
+	"write-tracer/internal/stream"
+	"write-tracer/internal/symbolize"
 
 	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
 	"github.com/cilium/ebpf/ringbuf"
 )
 
-func StartProcessing(ctx context.Context, cfg config.Config, eventsMap, trackedPidsMap *ebpf.Map) error {
-	rd, err := ringbuf.NewReader(eventsMap)
-	if err != nil {
-		return fmt.Errorf("create ring buffer reader: %w", err)
+// Processor holds handles to the running processing goroutines and the
+// eBPF resources they read from, so the caller can drain buffered output
+// and release kernel resources in one Shutdown call.
+type Processor struct {
+	coll      *ebpf.Collection
+	links     []link.Link
+	rd        *ringbuf.Reader
+	lifecycle *ringbuf.Reader
+	sinks     []output.EventSink
+
+	readDone      chan struct{}
+	processDone   chan struct{}
+	lifecycleDone chan struct{}
+}
+
+// Flush flushes every configured sink, returning the first error
+// encountered but still attempting the rest.
+func (p *Processor) Flush(ctx context.Context) error {
+	var firstErr error
+	for _, sink := range p.sinks {
+		if err := sink.Flush(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
 	}
+	return firstErr
+}
 
-	eventChan := make(chan event.WriteEvent, 1024)
+// Shutdown stops the tracer in order: it closes the ring buffer reader so
+// readRingBuffer stops accepting new samples, then waits for eventChan to
+// drain into the sinks. processEvents closes (and so flushes) every sink
+// itself once the drain finishes, so Shutdown doesn't flush them again here
+// — doing so after Close would hand a closed sink (e.g. FileWriter, whose
+// Close doesn't clear its *os.File) a Flush it can't honor. Finally it
+// releases the attached links and the eBPF collection. It returns ctx's
+// error if ctx ended before those goroutines finished.
+func (p *Processor) Shutdown(ctx context.Context) error {
+	p.rd.Close()
+	p.lifecycle.Close()
 
-	go processEvents(ctx, cfg, rd, eventChan)
-	go countTrackedPids(ctx, cfg.TrackingInterval, trackedPidsMap)
-	go readRingBuffer(ctx, rd, eventChan)
+	select {
+	case <-p.readDone:
+	case <-ctx.Done():
+	}
 
-	return nil
+	select {
+	case <-p.lifecycleDone:
+	case <-ctx.Done():
+	}
+
+	select {
+	case <-p.processDone:
+	case <-ctx.Done():
+	}
+	err := ctx.Err()
+
+	for _, l := range p.links {
+		l.Close()
+	}
+	p.coll.Close()
+
+	return err
 }
 
-func processEvents(ctx context.Context, cfg config.Config, rd *ringbuf.Reader, eventChan <-chan event.WriteEvent) {
-	defer rd.Close()
+// StartProcessing launches the goroutines that read events off the ring
+// buffer and dispatch them to the configured sinks and to hub's live
+// WebSocket and gRPC subscribers. cgroupInfo is nil unless the tracer was scoped to
+// a cgroup or container, in which case every decoded event is enriched
+// with its container_id/cgroup_path. symbolizer resolves each event's
+// captured stack (if any) into readable frames; pass nil to skip
+// symbolization entirely. registry is kept in sync with forked threads and
+// process exits reported on the lifecycle_events ring buffer.
+func StartProcessing(ctx context.Context, cfg config.Config, coll *ebpf.Collection, links []link.Link, cgroupInfo *container.Info, hub *stream.Hub, symbolizer *symbolize.Symbolizer, registry *pidmgr.PIDRegistry) (*Processor, error) {
+	rd, err := ringbuf.NewReader(coll.Maps["events"])
+	if err != nil {
+		return nil, fmt.Errorf("create ring buffer reader: %w", err)
+	}
 
-	fw := output.NewFileWriter(cfg.FileOutput, cfg.MaxRecordsFileOutput)
-	defer fw.Close()
+	lifecycleRd, err := ringbuf.NewReader(coll.Maps["lifecycle_events"])
+	if err != nil {
+		rd.Close()
+		return nil, fmt.Errorf("create lifecycle ring buffer reader: %w", err)
+	}
 
-	var loki *output.LokiClient
-	if cfg.LokiEndpoint != "" {
-		loki = output.NewLokiClient(cfg.LokiEndpoint)
+	sinks, err := output.BuildSinks(cfg)
+	if err != nil {
+		rd.Close()
+		lifecycleRd.Close()
+		return nil, fmt.Errorf("build sinks: %w", err)
 	}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case ev := <-eventChan:
-			line := ev.String()
-			fmt.Println(line)
-			output.IncrementWriteCalls()
+	sampler, err := sampling.New(cfg)
+	if err != nil {
+		rd.Close()
+		lifecycleRd.Close()
+		return nil, fmt.Errorf("build sampler: %w", err)
+	}
+
+	eventChan := make(chan event.WriteEvent, 1024)
+
+	var enrich func(*event.WriteEvent)
+	if cgroupInfo != nil {
+		enrich = func(ev *event.WriteEvent) {
+			ev.ContainerID = cgroupInfo.ContainerID
+			ev.CgroupPath = cgroupInfo.CgroupPath
+		}
+	}
 
-			if err := fw.Write(line); err != nil {
-				slog.Warn("File write failed", "error", err)
+	var resolveStack func(*event.WriteEvent)
+	if symbolizer != nil {
+		stacksMap := coll.Maps["stacks"]
+		resolveStack = func(ev *event.WriteEvent) {
+			if ev.StackID < 0 {
+				return
+			}
+			ips, err := lookupStack(stacksMap, ev.StackID)
+			if err != nil {
+				return
 			}
+			ev.Stack = symbolizer.Symbolize(ev.PID, ips)
+		}
+	}
+
+	p := &Processor{
+		coll:          coll,
+		links:         links,
+		rd:            rd,
+		lifecycle:     lifecycleRd,
+		sinks:         sinks,
+		readDone:      make(chan struct{}),
+		processDone:   make(chan struct{}),
+		lifecycleDone: make(chan struct{}),
+	}
 
-			if loki != nil {
-				go func(e event.WriteEvent) {
-					if err := loki.Push(e); err != nil {
-						slog.Warn("Loki push failed", "error", err)
-					}
-				}(ev)
+	go processEvents(eventChan, sinks, hub, p.processDone)
+	go countTrackedPids(ctx, cfg.TrackingInterval, coll.Maps["tracked_pids"])
+	go readRingBuffer(rd, eventChan, enrich, resolveStack, sampler, p.readDone)
+	go readLifecycleEvents(lifecycleRd, registry, p.lifecycleDone)
+
+	return p, nil
+}
+
+// processEvents dispatches every event off eventChan to the configured
+// sinks and to hub's live WebSocket and gRPC subscribers, then closes each sink once
+// readRingBuffer closes eventChan. This range-until-closed shape is what
+// lets Shutdown drain buffered events instead of dropping them on
+// cancellation.
+func processEvents(eventChan <-chan event.WriteEvent, sinks []output.EventSink, hub *stream.Hub, done chan<- struct{}) {
+	defer close(done)
+	defer closeSinks(sinks)
+
+	for ev := range eventChan {
+		output.IncrementWriteCalls()
+		for _, sink := range sinks {
+			if err := sink.Write(context.Background(), ev); err != nil {
+				slog.Warn("Sink write failed", "error", err)
 			}
 		}
+		hub.Publish(ev)
+	}
+}
+
+func closeSinks(sinks []output.EventSink) {
+	for _, sink := range sinks {
+		if err := sink.Close(); err != nil {
+			slog.Warn("Sink close failed", "error", err)
+		}
 	}
 }
 
@@ -88,7 +211,21 @@ func countTrackedPids(ctx context.Context, interval time.Duration, trackedPidsMa
 	}
 }
 
-func readRingBuffer(ctx context.Context, rd *ringbuf.Reader, eventChan chan<- event.WriteEvent) {
+// ringbufBacklogThreshold is how many unread bytes ringbuf.Record.Remaining
+// can report before we consider the consumer to be falling behind the
+// producer and count the record as an at-risk ("ringbuf_lost") sample.
+const ringbufBacklogThreshold = 1 << 20 // 1 MiB
+
+// readRingBuffer reads decoded events off rd until it is closed (by
+// Shutdown) or returns a non-ErrClosed error, closing eventChan on exit so
+// processEvents can drain whatever is still buffered and stop cleanly.
+// Every decoded event is run through sampler before being handed to
+// processEvents, so a traced process emitting faster than the pipeline can
+// keep up with is thinned out before it ever reaches eventChan.
+func readRingBuffer(rd *ringbuf.Reader, eventChan chan<- event.WriteEvent, enrich func(*event.WriteEvent), resolveStack func(*event.WriteEvent), sampler sampling.Sampler, done chan<- struct{}) {
+	defer close(done)
+	defer close(eventChan)
+
 	for {
 		record, err := rd.Read()
 		if err != nil {
@@ -99,17 +236,36 @@ func readRingBuffer(ctx context.Context, rd *ringbuf.Reader, eventChan chan<- ev
 			continue
 		}
 
-		var ev event.WriteEvent
-		if err := binary.Read(bytes.NewReader(record.RawSample), binary.LittleEndian, &ev); err != nil {
+		if record.Remaining > ringbufBacklogThreshold {
+			output.IncrementEventsDropped("ringbuf_lost")
+			slog.Warn("Ring buffer consumer falling behind", "remaining_bytes", record.Remaining)
+		}
+
+		ev, err := event.Decode(record.RawSample)
+		if err != nil {
+			output.IncrementEventsDropped("parse_error")
 			slog.Error("Event parse failed", "error", err)
 			continue
 		}
 
+		if enrich != nil {
+			enrich(&ev)
+		}
+		if resolveStack != nil {
+			resolveStack(&ev)
+		}
+
+		keep, weight := sampler.Admit(&ev)
+		if !keep {
+			output.IncrementEventsSampledOut()
+			continue
+		}
+		ev.SampleWeight = weight
+
 		select {
 		case eventChan <- ev:
-		case <-ctx.Done():
-			return
 		default:
+			output.IncrementEventsDropped("channel_full")
 			slog.Warn("Event channel full, dropping event")
 		}
 	}
@@ -0,0 +1,32 @@
+package ebpf
+
+import (
+	"fmt"
+
+	"github.com/cilium/ebpf"
+)
+
+// maxStackDepth bounds how many instruction pointers we'll read back for a
+// single stack trace, matching the depth the "stacks" BPF_MAP_TYPE_STACK_TRACE
+// map is sized for.
+const maxStackDepth = 32
+
+// lookupStack reads the raw user-space instruction pointers for stackID out
+// of stacksMap. A stackID < 0 means the kernel couldn't capture a stack for
+// that event (e.g. bpf_get_stackid returned -EEXIST/-ENOENT), so callers
+// should check that before calling this.
+func lookupStack(stacksMap *ebpf.Map, stackID int32) ([]uint64, error) {
+	var raw [maxStackDepth]uint64
+	if err := stacksMap.Lookup(uint32(stackID), &raw); err != nil {
+		return nil, fmt.Errorf("lookup stack %d: %w", stackID, err)
+	}
+
+	ips := make([]uint64, 0, maxStackDepth)
+	for _, ip := range raw {
+		if ip == 0 {
+			break
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
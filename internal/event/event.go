@@ -2,21 +2,106 @@ package event
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/json"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"write-tracer/internal/config"
+	"write-tracer/internal/symbolize"
 )
 
+// bootTimeNs is the wall-clock time (ns since epoch) at which the system
+// booted, read once from /proc/stat. WriteEvent.Timestamp is a kernel
+// CLOCK_MONOTONIC value (ns since boot), so adding bootTimeNs converts it
+// to an absolute wall-clock timestamp.
+var bootTimeNs = loadBootTimeNs()
+
+func loadBootTimeNs() int64 {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return 0
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		const prefix = "btime "
+		if strings.HasPrefix(line, prefix) {
+			secs, err := strconv.ParseInt(strings.TrimPrefix(line, prefix), 10, 64)
+			if err != nil {
+				return 0
+			}
+			return secs * int64(time.Second)
+		}
+	}
+	return 0
+}
+
+// kernelEvent mirrors the eBPF-emitted event layout exactly and is the
+// target of the ring buffer's binary.Read. WriteEvent carries everything
+// kernelEvent does plus fields enriched entirely in userspace, so it can't
+// be decoded into directly.
+type kernelEvent struct {
+	Timestamp uint64
+	Count     uint64
+	PID       uint32
+	TID       uint32
+	FD        uint32
+	// StackID is the id bpf_get_stackid returned for the caller's user
+	// stack (negative if the stack couldn't be captured, e.g. -ENOENT),
+	// looked up against the "stacks" BPF_MAP_TYPE_STACK_TRACE map.
+	StackID int32
+	Comm    [config.MaxExecNameSize]byte
+	Data    [config.MaxDataSize]byte
+}
+
 type WriteEvent struct {
 	Timestamp uint64                       `json:"timestamp"`
 	Count     uint64                       `json:"count"`
 	PID       uint32                       `json:"pid"`
 	TID       uint32                       `json:"tid"`
 	FD        uint32                       `json:"fd"`
-	_         uint32                       // padding
 	Comm      [config.MaxExecNameSize]byte `json:"comm"`
 	Data      [config.MaxDataSize]byte     `json:"data"`
+
+	// ContainerID and CgroupPath are populated by userspace enrichment
+	// (see internal/container) when the tracer is scoped to a cgroup or
+	// container rather than a single PID. Both are empty otherwise.
+	ContainerID string `json:"container_id,omitempty"`
+	CgroupPath  string `json:"cgroup_path,omitempty"`
+
+	// SampleWeight is 1/probability-of-admission, set by internal/sampling
+	// when the event survived sampling. Downstream aggregations multiply by
+	// this to reconstruct true counts. It is 0 (and omitted) for events that
+	// weren't sampled at all.
+	SampleWeight float64 `json:"sample_weight,omitempty"`
+
+	// Stack is the symbolized user stack for StackID, innermost frame
+	// first (the order the "stacks" map stores them in). Decode doesn't have access to the "stacks" map or a
+	// Symbolizer, so this stays empty until the caller resolves it (see
+	// internal/ebpf's stack lookup and internal/symbolize).
+	StackID int32             `json:"stack_id,omitempty"`
+	Stack   []symbolize.Frame `json:"stack,omitempty"`
+}
+
+// Decode parses a raw ring buffer record into a WriteEvent.
+func Decode(raw []byte) (WriteEvent, error) {
+	var k kernelEvent
+	if err := binary.Read(bytes.NewReader(raw), binary.LittleEndian, &k); err != nil {
+		return WriteEvent{}, err
+	}
+
+	return WriteEvent{
+		Timestamp: k.Timestamp,
+		Count:     k.Count,
+		PID:       k.PID,
+		TID:       k.TID,
+		FD:        k.FD,
+		Comm:      k.Comm,
+		Data:      k.Data,
+		StackID:   k.StackID,
+	}, nil
 }
 
 func (e WriteEvent) String() string {
@@ -35,6 +120,22 @@ func (e WriteEvent) String() string {
 		"count":     e.Count,
 		"data":      data,
 	}
+	if e.ContainerID != "" {
+		m["container_id"] = e.ContainerID
+	}
+	if e.CgroupPath != "" {
+		m["cgroup_path"] = e.CgroupPath
+	}
+	if e.SampleWeight != 0 {
+		m["sample_weight"] = e.SampleWeight
+	}
+	if len(e.Stack) > 0 {
+		frames := make([]string, len(e.Stack))
+		for i, f := range e.Stack {
+			frames[i] = f.String()
+		}
+		m["stack"] = frames
+	}
 
 	b, _ := json.Marshal(m)
 	return string(b)
@@ -48,3 +149,9 @@ func (e WriteEvent) DataString() string {
 	dataLen := min(e.Count, config.MaxDataSize)
 	return strings.TrimRight(string(e.Data[:dataLen]), "\n\r")
 }
+
+// WallTimeNanos converts the event's kernel (CLOCK_MONOTONIC) timestamp to
+// wall-clock nanoseconds since the Unix epoch.
+func (e WriteEvent) WallTimeNanos() int64 {
+	return bootTimeNs + int64(e.Timestamp)
+}
@@ -0,0 +1,158 @@
+// Package grpcapi serves the WriteTracer gRPC service defined in
+// proto/writetracer.proto: PID registry control RPCs plus a server-streaming
+// Events RPC, mirroring internal/api's REST/WebSocket endpoints for
+// consumers that want a typed, backpressure-aware interface (sidecars,
+// orchestrators) instead.
+package grpcapi
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative proto/writetracer.proto
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"write-tracer/internal/event"
+	"write-tracer/internal/grpcapi/pb"
+	"write-tracer/internal/pidmgr"
+	"write-tracer/internal/stream"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements pb.WriteTracerServer against the same PIDRegistry and
+// stream.Hub the REST/WebSocket API uses.
+type Server struct {
+	pb.UnimplementedWriteTracerServer
+
+	registry *pidmgr.PIDRegistry
+	hub      *stream.Hub
+	addr     string
+	grpc     *grpc.Server
+}
+
+// New creates a gRPC API server bound to addr (e.g. ":9090").
+func New(registry *pidmgr.PIDRegistry, hub *stream.Hub, addr string) *Server {
+	return &Server{registry: registry, hub: hub, addr: addr}
+}
+
+// Start begins serving the gRPC API in a goroutine.
+func (s *Server) Start() error {
+	lis, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", s.addr, err)
+	}
+
+	s.grpc = grpc.NewServer()
+	pb.RegisterWriteTracerServer(s.grpc, s)
+
+	go func() {
+		slog.Info("gRPC API server starting", "addr", s.addr)
+		if err := s.grpc.Serve(lis); err != nil {
+			slog.Error("gRPC API server failed", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop gracefully stops the gRPC server, waiting for in-flight RPCs
+// (including open Events streams) to finish.
+func (s *Server) Stop() {
+	if s.grpc != nil {
+		s.grpc.GracefulStop()
+	}
+}
+
+func (s *Server) RegisterPID(_ context.Context, req *pb.RegisterPIDRequest) (*pb.RegisterPIDResponse, error) {
+	threads, err := s.registry.RegisterPID(req.Pid)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &pb.RegisterPIDResponse{Pid: req.Pid, Threads: int32(threads)}, nil
+}
+
+func (s *Server) UnregisterPID(_ context.Context, req *pb.UnregisterPIDRequest) (*pb.UnregisterPIDResponse, error) {
+	if err := s.registry.UnregisterPID(req.Pid); err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return &pb.UnregisterPIDResponse{}, nil
+}
+
+func (s *Server) ListPIDs(_ context.Context, _ *pb.ListPIDsRequest) (*pb.ListPIDsResponse, error) {
+	procs := s.registry.List()
+
+	resp := &pb.ListPIDsResponse{Processes: make([]*pb.ProcessInfo, len(procs))}
+	for i, p := range procs {
+		resp.Processes[i] = &pb.ProcessInfo{
+			Pid:          p.ParentPID,
+			ThreadCount:  int32(len(p.ThreadIDs)),
+			RegisteredAt: p.RegisteredAt.Format(time.RFC3339),
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) RefreshThreads(_ context.Context, req *pb.RefreshThreadsRequest) (*pb.RefreshThreadsResponse, error) {
+	newCount, err := s.registry.RefreshThreads(req.Pid)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return &pb.RefreshThreadsResponse{NewThreads: int32(newCount)}, nil
+}
+
+// Events streams every write event matching req until the client cancels
+// or the stream send fails, subscribing to the same hub the WebSocket
+// handlers in internal/api use. It selects on stream.Context().Done()
+// alongside sub.Events() so an idle subscription (no events to notice the
+// disconnect via a failed Send) still gets unsubscribed as soon as the
+// client hangs up, instead of leaking until an event happens to arrive.
+func (s *Server) Events(req *pb.EventsRequest, stream pb.WriteTracer_EventsServer) error {
+	sub := s.hub.Subscribe(req.Pid)
+	defer s.hub.Unsubscribe(sub)
+
+	for {
+		select {
+		case ev, ok := <-sub.Events():
+			if !ok {
+				return nil
+			}
+			if !matchesFilter(req, ev) {
+				continue
+			}
+			if err := stream.Send(toProtoEvent(ev)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+func matchesFilter(req *pb.EventsRequest, ev event.WriteEvent) bool {
+	if req.Fd != 0 && ev.FD != req.Fd {
+		return false
+	}
+	if req.Comm != "" && ev.CommString() != req.Comm {
+		return false
+	}
+	return true
+}
+
+func toProtoEvent(ev event.WriteEvent) *pb.Event {
+	return &pb.Event{
+		Timestamp:   ev.Timestamp,
+		Count:       ev.Count,
+		Pid:         ev.PID,
+		Tid:         ev.TID,
+		Fd:          ev.FD,
+		Comm:        ev.CommString(),
+		Data:        ev.DataString(),
+		ContainerId: ev.ContainerID,
+		CgroupPath:  ev.CgroupPath,
+	}
+}
@@ -1,14 +1,20 @@
 package output
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+
+	"write-tracer/internal/event"
 )
 
+// FileWriter is an EventSink that appends events to a rotating file on
+// disk. It opens its file eagerly so construction failures are reported
+// to the caller rather than surfacing on the first write.
 type FileWriter struct {
 	path       string
 	maxRecords int
@@ -17,15 +23,41 @@ type FileWriter struct {
 	count      int
 }
 
-func NewFileWriter(path string, maxRecords int, maxBackups int) *FileWriter {
-	return &FileWriter{
+// NewFileWriter opens path for appending (rotating any existing file
+// aside first) and returns a FileWriter ready for writes. If path is
+// empty, the returned FileWriter is a no-op sink.
+func NewFileWriter(path string, maxRecords int, maxBackups int) (*FileWriter, error) {
+	w := &FileWriter{
 		path:       path,
 		maxRecords: maxRecords,
 		maxBackups: maxBackups,
 	}
+
+	if path == "" {
+		return w, nil
+	}
+
+	if err := w.open(); err != nil {
+		return nil, fmt.Errorf("open file output %s: %w", path, err)
+	}
+
+	return w, nil
+}
+
+// Write appends ev's line-oriented representation to the file.
+func (w *FileWriter) Write(_ context.Context, ev event.WriteEvent) error {
+	return w.writeLine(ev.String())
+}
+
+// Flush fsyncs the underlying file.
+func (w *FileWriter) Flush(_ context.Context) error {
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
 }
 
-func (w *FileWriter) Write(line string) error {
+func (w *FileWriter) writeLine(line string) error {
 	if w.path == "" {
 		return nil
 	}
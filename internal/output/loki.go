@@ -2,18 +2,49 @@ package output
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"math/rand"
 	"net/http"
+	"sync"
 	"time"
 
 	"write-tracer/internal/event"
 )
 
+const (
+	lokiQueueSize     = 4096
+	lokiMaxBatchSize  = 1000
+	lokiMaxBatchBytes = 1 << 20 // 1 MiB
+	lokiBatchInterval = time.Second
+	lokiMaxRetries    = 5
+	lokiBackoffBase   = 200 * time.Millisecond
+	lokiBackoffMax    = 10 * time.Second
+)
+
+// LokiClient batches write events by stream labels and pushes them to a
+// Loki push endpoint in the background. Push is non-blocking: events are
+// enqueued onto a bounded channel and aggregated by a single worker
+// goroutine, which flushes on batch size, batch bytes, or a timer,
+// whichever comes first.
+//
+// LokiClient implements EventSink directly rather than living behind a
+// dedicated Loki-only package: every sink (stdout, file, Loki, OTLP) shares
+// the same interface, construction path (BuildSinks), and metrics, and
+// splitting one of them out would mean duplicating that plumbing for no
+// behavioral gain.
 type LokiClient struct {
 	endpoint string
 	client   *http.Client
+
+	queue   chan event.WriteEvent
+	flushCh chan chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
 }
 
 type lokiPushRequest struct {
@@ -25,49 +56,258 @@ type lokiStream struct {
 	Values [][]string        `json:"values"`
 }
 
+// lokiStreamKey identifies a Loki stream by its label set.
+type lokiStreamKey struct {
+	pid  uint32
+	comm string
+	fd   uint32
+}
+
 func NewLokiClient(endpoint string) *LokiClient {
-	return &LokiClient{
+	l := &LokiClient{
 		endpoint: endpoint,
 		client:   &http.Client{Timeout: 5 * time.Second},
+		queue:    make(chan event.WriteEvent, lokiQueueSize),
+		flushCh:  make(chan chan struct{}),
+		done:     make(chan struct{}),
 	}
+
+	l.wg.Add(1)
+	go l.run()
+
+	return l
 }
 
+// Push enqueues an event for batched delivery to Loki. It never blocks on
+// the network; if the internal queue is full the event is dropped.
 func (l *LokiClient) Push(ev event.WriteEvent) error {
+	select {
+	case l.queue <- ev:
+		lokiQueueDepth.Inc()
+		return nil
+	default:
+		lokiPushesTotal.WithLabelValues("dropped").Inc()
+		return fmt.Errorf("loki queue full, event dropped")
+	}
+}
+
+// Write implements EventSink by enqueueing ev for batched delivery; ctx is
+// unused since Push never blocks on the network.
+func (l *LokiClient) Write(_ context.Context, ev event.WriteEvent) error {
+	return l.Push(ev)
+}
+
+// Flush forces out any events currently queued or held in the in-progress
+// batch, waiting until they've been sent (or exhausted their retries) or
+// ctx is done.
+func (l *LokiClient) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+
+	select {
+	case l.flushCh <- ack:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-l.done:
+		return nil
+	}
+
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the background worker after flushing any queued events.
+func (l *LokiClient) Close() error {
+	close(l.done)
+	l.wg.Wait()
+	return nil
+}
+
+func (l *LokiClient) run() {
+	defer l.wg.Done()
+
+	streams := make(map[lokiStreamKey]*lokiStream)
+	count, size := 0, 0
+
+	ticker := time.NewTicker(lokiBatchInterval)
+	defer ticker.Stop()
+
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		l.send(streams)
+		streams = make(map[lokiStreamKey]*lokiStream)
+		count, size = 0, 0
+	}
+
+	for {
+		select {
+		case ev := <-l.queue:
+			lokiQueueDepth.Dec()
+			size += l.add(streams, ev)
+			count++
+			if count >= lokiMaxBatchSize || size >= lokiMaxBatchBytes {
+				flush()
+			}
+
+		case <-ticker.C:
+			flush()
+
+		case ack := <-l.flushCh:
+			l.drainQueue(streams, &count, &size)
+			flush()
+			close(ack)
 
-	stream := lokiStream{
-		Stream: map[string]string{
-			"app":  "write-tracer",
-			"pid":  fmt.Sprintf("%d", ev.PID),
-			"comm": ev.CommString(),
-			"fd":   fmt.Sprintf("%d", ev.FD),
-		},
-		Values: [][]string{
-			{fmt.Sprintf("%d", time.Now().UnixNano()), ev.DataString()},
-		},
+		case <-l.done:
+			l.drainQueue(streams, &count, &size)
+			flush()
+			return
+		}
+	}
+}
+
+// drainQueue pulls every event currently sitting in the queue into streams
+// without blocking, so a Flush/shutdown doesn't leave events stranded
+// behind whatever is left of the batch interval.
+func (l *LokiClient) drainQueue(streams map[lokiStreamKey]*lokiStream, count, size *int) {
+	for {
+		select {
+		case ev := <-l.queue:
+			lokiQueueDepth.Dec()
+			*size += l.add(streams, ev)
+			*count++
+		default:
+			return
+		}
+	}
+}
+
+// add appends ev to its stream's values, creating the stream if needed, and
+// returns the approximate number of bytes it contributed to the batch.
+func (l *LokiClient) add(streams map[lokiStreamKey]*lokiStream, ev event.WriteEvent) int {
+	key := lokiStreamKey{pid: ev.PID, comm: ev.CommString(), fd: ev.FD}
+
+	s, ok := streams[key]
+	if !ok {
+		s = &lokiStream{
+			Stream: map[string]string{
+				"app":  "write-tracer",
+				"pid":  fmt.Sprintf("%d", key.pid),
+				"comm": key.comm,
+				"fd":   fmt.Sprintf("%d", key.fd),
+			},
+		}
+		if ev.ContainerID != "" {
+			s.Stream["container_id"] = ev.ContainerID
+		}
+		if ev.CgroupPath != "" {
+			s.Stream["cgroup_path"] = ev.CgroupPath
+		}
+		streams[key] = s
+	}
+
+	ts := fmt.Sprintf("%d", ev.WallTimeNanos())
+	data := ev.DataString()
+	s.Values = append(s.Values, []string{ts, data})
+
+	return len(ts) + len(data)
+}
+
+// lokiStatusError reports a non-2xx Loki response, carrying the status code
+// so send can tell a retryable outage (429/5xx) from a request that will
+// never succeed (e.g. a malformed payload).
+type lokiStatusError struct {
+	status int
+	body   string
+}
+
+func (e *lokiStatusError) Error() string {
+	return fmt.Sprintf("loki returned status %d: %s", e.status, e.body)
+}
+
+// retryable reports whether status indicates a transient failure worth
+// retrying: 429 (rate limited) or any 5xx (server-side outage).
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// send marshals the batch and POSTs it to the Loki endpoint, retrying with
+// exponential backoff and jitter on transport errors and 429/5xx responses.
+// Any other non-2xx response is treated as non-retryable, since resending
+// the same payload won't change the outcome. send runs on the same goroutine
+// as run()'s select loop, so a backoff sleep would otherwise stop run() from
+// noticing l.done until the sleep finally returns; the retry loop selects on
+// l.done directly so Close (which closes l.done before waiting on l.wg)
+// interrupts a pending retry instead of waiting it out.
+func (l *LokiClient) send(streams map[lokiStreamKey]*lokiStream) {
+	req := lokiPushRequest{Streams: make([]lokiStream, 0, len(streams))}
+	for _, s := range streams {
+		req.Streams = append(req.Streams, *s)
 	}
 
-	req := lokiPushRequest{Streams: []lokiStream{stream}}
 	body, err := json.Marshal(req)
 	if err != nil {
-		return err
+		slog.Error("Loki batch marshal failed", "error", err)
+		lokiPushesTotal.WithLabelValues("failed").Inc()
+		return
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= lokiMaxRetries; attempt++ {
+		if attempt > 0 {
+			lokiPushRetriesTotal.Inc()
+			select {
+			case <-time.After(lokiBackoff(attempt)):
+			case <-l.done:
+				slog.Warn("Loki batch push aborted by shutdown", "streams", len(streams))
+				lokiPushesTotal.WithLabelValues("failed").Inc()
+				return
+			}
+		}
+
+		lastErr = l.post(body)
+		if lastErr == nil {
+			lokiPushesTotal.WithLabelValues("ok").Inc()
+			return
+		}
+
+		var statusErr *lokiStatusError
+		if errors.As(lastErr, &statusErr) && !retryable(statusErr.status) {
+			slog.Warn("Loki batch push rejected, not retrying", "error", lastErr, "streams", len(streams))
+			lokiPushesTotal.WithLabelValues("failed").Inc()
+			return
+		}
 	}
 
-	// DEBUG: Get the loki push request body
-	// slog.Info("Loki push request", "body", string(body))
+	slog.Warn("Loki batch push failed after retries", "error", lastErr, "streams", len(streams))
+	lokiPushesTotal.WithLabelValues("failed").Inc()
+}
 
-	resp, err := l.client.Post(l.endpoint, "application/json", bytes.NewBuffer(body))
+func (l *LokiClient) post(body []byte) error {
+	resp, err := l.client.Post(l.endpoint, "application/json", bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		// DEBUG: Read response body to see what Loki is complaining about
 		respBody, _ := io.ReadAll(resp.Body)
-
-		return fmt.Errorf("loki returned status %d: %s", resp.StatusCode, string(respBody))
-		// return fmt.Errorf("loki returned status %d", resp.StatusCode)
+		return &lokiStatusError{status: resp.StatusCode, body: string(respBody)}
 	}
 
 	return nil
 }
+
+// lokiBackoff returns the delay before retry attempt n (1-indexed), using
+// exponential backoff capped at lokiBackoffMax with up to 50% jitter.
+func lokiBackoff(attempt int) time.Duration {
+	d := lokiBackoffBase << uint(attempt-1)
+	if d <= 0 || d > lokiBackoffMax {
+		d = lokiBackoffMax
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d)/2+1))
+}
@@ -19,9 +19,45 @@ var writeCalls = prometheus.NewCounter(prometheus.CounterOpts{
 	Help: "Total number of write calls captured",
 })
 
+var lokiPushesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "write_tracer_loki_pushes_total",
+	Help: "Total number of Loki batch pushes by outcome (ok, failed), plus single events dropped because the queue was full (dropped)",
+}, []string{"status"})
+
+var lokiQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "write_tracer_loki_queue_depth",
+	Help: "Number of events currently queued for delivery to Loki",
+})
+
+var lokiPushRetriesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "write_tracer_loki_push_retries_total",
+	Help: "Total number of retried Loki batch push attempts (429/5xx responses or transport errors)",
+})
+
+var eventsDroppedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "write_tracer_events_dropped_total",
+	Help: "Total number of captured events dropped before reaching a sink, by reason",
+}, []string{"reason"})
+
+var eventsSampledOutTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "write_tracer_events_sampled_out_total",
+	Help: "Total number of captured events discarded by the configured sampler",
+})
+
+var streamEventsDroppedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "write_tracer_stream_events_dropped_total",
+	Help: "Total number of events dropped for WebSocket or gRPC stream subscribers that weren't draining fast enough",
+})
+
 func init() {
 	prometheus.MustRegister(trackedThreads)
 	prometheus.MustRegister(writeCalls)
+	prometheus.MustRegister(lokiPushesTotal)
+	prometheus.MustRegister(lokiQueueDepth)
+	prometheus.MustRegister(lokiPushRetriesTotal)
+	prometheus.MustRegister(eventsDroppedTotal)
+	prometheus.MustRegister(eventsSampledOutTotal)
+	prometheus.MustRegister(streamEventsDroppedTotal)
 }
 
 func UpdateTrackedThreads(count int) {
@@ -32,6 +68,24 @@ func IncrementWriteCalls() {
 	writeCalls.Inc()
 }
 
+// IncrementEventsDropped records an event dropped before it reached a sink.
+// reason is one of "channel_full", "parse_error", or "ringbuf_lost".
+func IncrementEventsDropped(reason string) {
+	eventsDroppedTotal.WithLabelValues(reason).Inc()
+}
+
+// IncrementEventsSampledOut records an event the configured Sampler chose
+// not to admit.
+func IncrementEventsSampledOut() {
+	eventsSampledOutTotal.Inc()
+}
+
+// IncrementStreamEventsDropped records an event dropped for a slow
+// WebSocket or gRPC stream subscriber.
+func IncrementStreamEventsDropped() {
+	streamEventsDroppedTotal.Inc()
+}
+
 func StartMetricsServer(port int) error {
 	if port <= 0 {
 		return nil
@@ -0,0 +1,199 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"write-tracer/internal/event"
+)
+
+const (
+	otlpMaxBatchSize  = 500
+	otlpBatchInterval = time.Second
+
+	// otlpSeverityInfo is SEVERITY_NUMBER_INFO from the OTLP logs data model.
+	otlpSeverityInfo = 9
+)
+
+// OTLPSink exports write events as OpenTelemetry log records, batched and
+// POSTed as a ResourceLogs payload to an OTLP/HTTP logs collector.
+type OTLPSink struct {
+	endpoint string
+	client   *http.Client
+
+	mu      sync.Mutex
+	records []otlpLogRecord
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+type otlpExportRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano   string          `json:"timeUnixNano"`
+	SeverityText   string          `json:"severityText"`
+	SeverityNumber int             `json:"severityNumber"`
+	Body           otlpAnyValue    `json:"body"`
+	Attributes     []otlpAttribute `json:"attributes"`
+}
+
+type otlpAttribute struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// NewOTLPSink returns an OTLPSink that exports to endpoint, flushing
+// whatever it's buffered every otlpBatchInterval in the background.
+func NewOTLPSink(endpoint string) (*OTLPSink, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("otlp sink requires an endpoint")
+	}
+
+	s := &OTLPSink{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 5 * time.Second},
+		done:     make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.run()
+
+	return s, nil
+}
+
+func (s *OTLPSink) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(otlpBatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Flush(context.Background()); err != nil {
+				slog.Warn("OTLP periodic flush failed", "error", err)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Write buffers ev as an OTLP log record, flushing immediately if the
+// batch has reached otlpMaxBatchSize.
+func (s *OTLPSink) Write(ctx context.Context, ev event.WriteEvent) error {
+	s.mu.Lock()
+	s.records = append(s.records, toLogRecord(ev))
+	full := len(s.records) >= otlpMaxBatchSize
+	s.mu.Unlock()
+
+	if full {
+		return s.Flush(ctx)
+	}
+	return nil
+}
+
+// Flush POSTs whatever log records are currently buffered as a single
+// ResourceLogs batch.
+func (s *OTLPSink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	records := s.records
+	s.records = nil
+	s.mu.Unlock()
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	req := otlpExportRequest{
+		ResourceLogs: []otlpResourceLogs{{
+			Resource: otlpResource{
+				Attributes: []otlpAttribute{strAttr("service.name", "write-tracer")},
+			},
+			ScopeLogs: []otlpScopeLogs{{LogRecords: records}},
+		}},
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("marshal otlp export request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build otlp export request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("otlp export failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("otlp collector returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Close stops the background flush loop and sends any remaining records.
+func (s *OTLPSink) Close() error {
+	close(s.done)
+	s.wg.Wait()
+	return s.Flush(context.Background())
+}
+
+func toLogRecord(ev event.WriteEvent) otlpLogRecord {
+	attrs := []otlpAttribute{
+		strAttr("process.pid", fmt.Sprintf("%d", ev.PID)),
+		strAttr("thread.id", fmt.Sprintf("%d", ev.TID)),
+		strAttr("process.executable.name", ev.CommString()),
+		strAttr("file.descriptor", fmt.Sprintf("%d", ev.FD)),
+	}
+	if ev.ContainerID != "" {
+		attrs = append(attrs, strAttr("container.id", ev.ContainerID))
+	}
+	if ev.CgroupPath != "" {
+		attrs = append(attrs, strAttr("cgroup.path", ev.CgroupPath))
+	}
+
+	return otlpLogRecord{
+		TimeUnixNano:   fmt.Sprintf("%d", ev.WallTimeNanos()),
+		SeverityText:   "INFO",
+		SeverityNumber: otlpSeverityInfo,
+		Body:           otlpAnyValue{StringValue: ev.DataString()},
+		Attributes:     attrs,
+	}
+}
+
+func strAttr(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpAnyValue{StringValue: value}}
+}
@@ -0,0 +1,85 @@
+package output
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"write-tracer/internal/config"
+	"write-tracer/internal/event"
+)
+
+// EventSink receives decoded write events for delivery to some backend
+// (stdout, a file, Loki, an OTLP collector, ...). processEvents fans out
+// to every configured sink; a failing sink's error is logged and does not
+// stop delivery to the others. Sinks are written one after another on the
+// same goroutine, so a sink whose Write blocks delays the rest.
+type EventSink interface {
+	Write(ctx context.Context, ev event.WriteEvent) error
+	Flush(ctx context.Context) error
+	Close() error
+}
+
+// StdoutSink writes each event's line-oriented representation to stdout.
+type StdoutSink struct{}
+
+func NewStdoutSink() *StdoutSink { return &StdoutSink{} }
+
+func (StdoutSink) Write(_ context.Context, ev event.WriteEvent) error {
+	fmt.Println(ev.String())
+	return nil
+}
+
+func (StdoutSink) Flush(context.Context) error { return nil }
+func (StdoutSink) Close() error                { return nil }
+
+// BuildSinks constructs the EventSinks selected by cfg.Sinks. Construction
+// errors (a sink that can't open its output) are returned immediately so
+// callers can fail at startup instead of nil-checking sink pointers deep
+// inside the hot loop.
+func BuildSinks(cfg config.Config) ([]EventSink, error) {
+	var sinks []EventSink
+
+	for _, name := range cfg.Sinks {
+		switch strings.TrimSpace(name) {
+		case "":
+			// tolerate trailing/doubled commas in --sink
+
+		case "stdout":
+			if !cfg.SilenceStdout {
+				sinks = append(sinks, NewStdoutSink())
+			}
+
+		case "file":
+			if cfg.FileOutput == "" {
+				continue
+			}
+			fw, err := NewFileWriter(cfg.FileOutput, cfg.MaxRecordsFileOutput, 0)
+			if err != nil {
+				return nil, fmt.Errorf("build file sink: %w", err)
+			}
+			sinks = append(sinks, fw)
+
+		case "loki":
+			if cfg.LokiEndpoint == "" {
+				continue
+			}
+			sinks = append(sinks, NewLokiClient(cfg.LokiEndpoint))
+
+		case "otlp":
+			if cfg.OTLPEndpoint == "" {
+				return nil, fmt.Errorf("build otlp sink: --otlp-endpoint is required when \"otlp\" is in --sink")
+			}
+			otlp, err := NewOTLPSink(cfg.OTLPEndpoint)
+			if err != nil {
+				return nil, fmt.Errorf("build otlp sink: %w", err)
+			}
+			sinks = append(sinks, otlp)
+
+		default:
+			return nil, fmt.Errorf("unknown sink %q", name)
+		}
+	}
+
+	return sinks, nil
+}
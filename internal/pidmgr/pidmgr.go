@@ -1,6 +1,8 @@
 // Package pidmgr provides thread-safe management of tracked process IDs.
 // It maintains a registry of parent PIDs and their threads, updating the eBPF
-// tracked_pids map and automatically cleaning up when processes terminate.
+// tracked_pids map and automatically cleaning up when processes terminate
+// (via HandleExit, driven by sched_process_exit events, with periodic
+// liveness polling as a backstop).
 package pidmgr
 
 import (
@@ -28,6 +30,7 @@ type PIDRegistry struct {
 	trackedPids   map[uint32]*TrackedProcess // parent PID -> process info
 	ebpfMap       *ebpf.Map                  // tracked_pids eBPF map
 	checkInterval time.Duration
+	exitHooks     []func(pid uint32)
 }
 
 // New creates a new PIDRegistry with the given eBPF tracked_pids map.
@@ -43,6 +46,23 @@ func New(ebpfMap *ebpf.Map, checkInterval time.Duration) *PIDRegistry {
 	}
 }
 
+// AddExitHook registers fn to be called, with the parent PID, whenever a
+// tracked process is removed from the registry - whether explicitly via
+// UnregisterPID, by HandleExit on a sched_process_exit event, or
+// automatically by the liveness monitor. Callers that cache
+// per-PID state (e.g. symbolize.Symbolizer) should use this to invalidate it.
+func (r *PIDRegistry) AddExitHook(fn func(pid uint32)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exitHooks = append(r.exitHooks, fn)
+}
+
+func (r *PIDRegistry) runExitHooks(pid uint32) {
+	for _, fn := range r.exitHooks {
+		fn(pid)
+	}
+}
+
 // RegisterPID adds a parent PID and all its threads to the tracking registry.
 // Returns the number of threads found, or an error if the process doesn't exist.
 func (r *PIDRegistry) RegisterPID(pid uint32) (int, error) {
@@ -101,9 +121,69 @@ func (r *PIDRegistry) UnregisterPID(pid uint32) error {
 
 	delete(r.trackedPids, pid)
 	slog.Info("Unregistered PID from tracking", "pid", pid)
+	r.runExitHooks(pid)
 	return nil
 }
 
+// HandleFork records a forked child/thread reported by the
+// sched_process_fork tracepoint. The eBPF program has already inserted
+// childTID into the tracked_pids map (only doing so when parentTID was
+// already tracked), so this just keeps ThreadIDs in sync for List()/GET
+// /pids/{pid}. It's a no-op if parentTID doesn't belong to any tracked
+// process, which can happen if the fork event is processed after the
+// parent has already exited.
+func (r *PIDRegistry) HandleFork(parentTID, childTID uint32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, proc := range r.trackedPids {
+		if proc.ParentPID == parentTID || containsTID(proc.ThreadIDs, parentTID) {
+			proc.ThreadIDs = append(proc.ThreadIDs, childTID)
+			return
+		}
+	}
+}
+
+// HandleExit records a thread exit reported by the sched_process_exit
+// tracepoint, replacing the need to poll for liveness. The eBPF program
+// has already removed tid from the tracked_pids map. If tid is a tracked
+// process's parent PID, the whole process is unregistered; otherwise tid
+// is dropped from its owning process's ThreadIDs.
+func (r *PIDRegistry) HandleExit(tid uint32) {
+	r.mu.Lock()
+
+	if _, exists := r.trackedPids[tid]; exists {
+		delete(r.trackedPids, tid)
+		r.mu.Unlock()
+		slog.Info("Auto-removed terminated process", "pid", tid)
+		r.runExitHooks(tid)
+		return
+	}
+
+	for _, proc := range r.trackedPids {
+		proc.ThreadIDs = removeTID(proc.ThreadIDs, tid)
+	}
+	r.mu.Unlock()
+}
+
+func containsTID(tids []uint32, tid uint32) bool {
+	for _, t := range tids {
+		if t == tid {
+			return true
+		}
+	}
+	return false
+}
+
+func removeTID(tids []uint32, tid uint32) []uint32 {
+	for i, t := range tids {
+		if t == tid {
+			return append(tids[:i], tids[i+1:]...)
+		}
+	}
+	return tids
+}
+
 // List returns a copy of all currently tracked processes.
 func (r *PIDRegistry) List() []TrackedProcess {
 	r.mu.RLock()
@@ -127,6 +207,10 @@ func (r *PIDRegistry) IsRegistered(pid uint32) bool {
 // StartLivenessMonitor starts a goroutine that periodically checks if tracked
 // processes are still alive. Dead processes are automatically unregistered.
 // The monitor stops when the context is cancelled.
+//
+// HandleExit already unregisters processes as soon as their
+// sched_process_exit event arrives, so this polling loop is now a backstop
+// for events the ringbuf dropped rather than the primary cleanup path.
 func (r *PIDRegistry) StartLivenessMonitor(ctx context.Context) {
 	go func() {
 		ticker := time.NewTicker(r.checkInterval)
@@ -156,6 +240,7 @@ func (r *PIDRegistry) checkLiveness() {
 			}
 			delete(r.trackedPids, pid)
 			slog.Info("Auto-removed terminated process", "pid", pid)
+			r.runExitHooks(pid)
 		}
 	}
 }
@@ -0,0 +1,41 @@
+package sampling
+
+import (
+	"sync"
+
+	"write-tracer/internal/event"
+)
+
+// fixedRateSampler keeps 1 of every n events per (PID, FD) tuple.
+type fixedRateSampler struct {
+	n uint64
+
+	mu     sync.Mutex
+	counts map[fixedRateKey]uint64
+}
+
+type fixedRateKey struct {
+	pid uint32
+	fd  uint32
+}
+
+func newFixedRateSampler(n int) *fixedRateSampler {
+	return &fixedRateSampler{
+		n:      uint64(n),
+		counts: make(map[fixedRateKey]uint64),
+	}
+}
+
+func (s *fixedRateSampler) Admit(ev *event.WriteEvent) (bool, float64) {
+	key := fixedRateKey{pid: ev.PID, fd: ev.FD}
+
+	s.mu.Lock()
+	count := s.counts[key]
+	s.counts[key] = count + 1
+	s.mu.Unlock()
+
+	if count%s.n != 0 {
+		return false, 0
+	}
+	return true, float64(s.n)
+}
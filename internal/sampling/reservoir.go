@@ -0,0 +1,53 @@
+package sampling
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"write-tracer/internal/event"
+)
+
+// reservoirSampler applies algorithm R's acceptance rule online within
+// each window: the first k events of a window are admitted with weight 1,
+// and the i-th event after that is admitted with probability k/i and
+// weight i/k. Admitted events are dispatched downstream at once and never
+// revoked, so a window of n events admits about k*(1+ln(n/k)) of them
+// rather than exactly k, and events early in a window are more likely to
+// be admitted than later ones.
+type reservoirSampler struct {
+	k      int64
+	window time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	seen        int64
+}
+
+func newReservoirSampler(k int, window time.Duration) *reservoirSampler {
+	return &reservoirSampler{
+		k:           int64(k),
+		window:      window,
+		windowStart: time.Now(),
+	}
+}
+
+func (s *reservoirSampler) Admit(*event.WriteEvent) (bool, float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.windowStart) >= s.window {
+		s.windowStart = now
+		s.seen = 0
+	}
+	s.seen++
+
+	if s.seen <= s.k {
+		return true, 1
+	}
+	if rand.Int63n(s.seen) < s.k {
+		return true, float64(s.seen) / float64(s.k)
+	}
+	return false, 0
+}
@@ -0,0 +1,58 @@
+// Package sampling decides which captured write events survive to the
+// configured sinks when a traced process is emitting faster than the
+// pipeline can usefully record, flagging each survivor with the weight
+// needed to reconstruct true counts downstream.
+package sampling
+
+import (
+	"fmt"
+
+	"write-tracer/internal/config"
+	"write-tracer/internal/event"
+)
+
+// Sampler decides whether an event should be forwarded to the sinks. When
+// keep is true, weight is 1/probability-of-admission and should be copied
+// onto ev.SampleWeight by the caller.
+type Sampler interface {
+	Admit(ev *event.WriteEvent) (keep bool, weight float64)
+}
+
+// noopSampler admits everything and leaves SampleWeight unset (0, so it's
+// omitted from JSON output); it's the Sampler used when --sample-mode is
+// "none".
+type noopSampler struct{}
+
+func (noopSampler) Admit(*event.WriteEvent) (bool, float64) { return true, 0 }
+
+// New returns the Sampler selected by cfg.SampleMode.
+func New(cfg config.Config) (Sampler, error) {
+	switch cfg.SampleMode {
+	case "", "none":
+		return noopSampler{}, nil
+
+	case "fixed-rate":
+		if cfg.SampleRate <= 0 {
+			return nil, fmt.Errorf("fixed-rate sampling requires --sample-rate > 0")
+		}
+		return newFixedRateSampler(cfg.SampleRate), nil
+
+	case "token-bucket":
+		if cfg.SampleRPS <= 0 {
+			return nil, fmt.Errorf("token-bucket sampling requires --sample-rps > 0")
+		}
+		return newTokenBucketSampler(cfg.SampleRPS, cfg.SampleBurst, cfg.SamplePerPID), nil
+
+	case "reservoir":
+		if cfg.SampleRate <= 0 {
+			return nil, fmt.Errorf("reservoir sampling requires --sample-rate > 0 (reservoir size)")
+		}
+		if cfg.SampleWindow <= 0 {
+			return nil, fmt.Errorf("reservoir sampling requires --sample-window > 0")
+		}
+		return newReservoirSampler(cfg.SampleRate, cfg.SampleWindow), nil
+
+	default:
+		return nil, fmt.Errorf("unknown sample mode %q", cfg.SampleMode)
+	}
+}
@@ -0,0 +1,69 @@
+package sampling
+
+import (
+	"sync"
+
+	"write-tracer/internal/event"
+
+	"golang.org/x/time/rate"
+)
+
+// tokenBucketSampler admits events up to an events/sec limit with burst,
+// either against one global bucket or one bucket per PID. Admission here is
+// a hard limit rather than a probability, so admitted events keep weight 1
+// instead of an estimated 1/probability.
+type tokenBucketSampler struct {
+	perPID bool
+	limit  rate.Limit
+	burst  int
+
+	global *rate.Limiter
+
+	mu       sync.Mutex
+	limiters map[uint32]*rate.Limiter
+}
+
+func newTokenBucketSampler(rps float64, burst int, perPID bool) *tokenBucketSampler {
+	if burst <= 0 {
+		burst = int(rps)
+		if burst <= 0 {
+			burst = 1
+		}
+	}
+
+	s := &tokenBucketSampler{
+		perPID: perPID,
+		limit:  rate.Limit(rps),
+		burst:  burst,
+	}
+	if perPID {
+		s.limiters = make(map[uint32]*rate.Limiter)
+	} else {
+		s.global = rate.NewLimiter(s.limit, s.burst)
+	}
+	return s
+}
+
+func (s *tokenBucketSampler) Admit(ev *event.WriteEvent) (bool, float64) {
+	limiter := s.global
+	if s.perPID {
+		limiter = s.limiterFor(ev.PID)
+	}
+
+	if !limiter.Allow() {
+		return false, 0
+	}
+	return true, 1
+}
+
+func (s *tokenBucketSampler) limiterFor(pid uint32) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.limiters[pid]
+	if !ok {
+		l = rate.NewLimiter(s.limit, s.burst)
+		s.limiters[pid] = l
+	}
+	return l
+}
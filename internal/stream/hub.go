@@ -0,0 +1,106 @@
+// Package stream fans out decoded write events to live subscribers (the
+// WebSocket handlers in internal/api and the gRPC Events stream in
+// internal/grpcapi), independently of the sinks in internal/output.
+package stream
+
+import (
+	"sync"
+
+	"write-tracer/internal/event"
+	"write-tracer/internal/output"
+)
+
+// subscriberBufferSize is how many events a slow subscriber can fall
+// behind by before its events start getting dropped.
+const subscriberBufferSize = 256
+
+// Subscriber receives write events for one PID, or every event if pid is 0
+// (the global /events topic).
+type Subscriber struct {
+	pid uint32
+	ch  chan event.WriteEvent
+}
+
+// Events returns the channel new events arrive on.
+func (s *Subscriber) Events() <-chan event.WriteEvent {
+	return s.ch
+}
+
+// Hub fans out published events to every subscriber whose topic matches:
+// the global topic always matches, a per-PID topic matches events for that
+// PID.
+type Hub struct {
+	mu     sync.RWMutex
+	global map[*Subscriber]struct{}
+	byPID  map[uint32]map[*Subscriber]struct{}
+}
+
+// NewHub returns an empty Hub.
+func NewHub() *Hub {
+	return &Hub{
+		global: make(map[*Subscriber]struct{}),
+		byPID:  make(map[uint32]map[*Subscriber]struct{}),
+	}
+}
+
+// Subscribe registers a new Subscriber for pid's events, or for every event
+// if pid is 0. Callers must Unsubscribe when done to avoid leaking it.
+func (h *Hub) Subscribe(pid uint32) *Subscriber {
+	sub := &Subscriber{pid: pid, ch: make(chan event.WriteEvent, subscriberBufferSize)}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if pid == 0 {
+		h.global[sub] = struct{}{}
+		return sub
+	}
+
+	subs, ok := h.byPID[pid]
+	if !ok {
+		subs = make(map[*Subscriber]struct{})
+		h.byPID[pid] = subs
+	}
+	subs[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe removes sub from the hub and closes its channel.
+func (h *Hub) Unsubscribe(sub *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if sub.pid == 0 {
+		delete(h.global, sub)
+	} else if subs, ok := h.byPID[sub.pid]; ok {
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(h.byPID, sub.pid)
+		}
+	}
+	close(sub.ch)
+}
+
+// Publish fans ev out to every matching subscriber. Delivery is
+// non-blocking: a subscriber that isn't draining its channel fast enough
+// has the event dropped and write_tracer_stream_events_dropped_total
+// incremented instead of blocking the processing pipeline.
+func (h *Hub) Publish(ev event.WriteEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.global {
+		send(sub, ev)
+	}
+	for sub := range h.byPID[ev.PID] {
+		send(sub, ev)
+	}
+}
+
+func send(sub *Subscriber, ev event.WriteEvent) {
+	select {
+	case sub.ch <- ev:
+	default:
+		output.IncrementStreamEventsDropped()
+	}
+}
@@ -0,0 +1,106 @@
+package symbolize
+
+import (
+	"debug/elf"
+	"debug/gosym"
+	"fmt"
+	"sort"
+)
+
+// moduleTable resolves a file-relative offset within one executable to a
+// function name and the offset's distance into that function. Go binaries
+// are resolved via their pclntab (the same mechanism pprof uses); anything
+// else falls back to the regular ELF symbol table.
+type moduleTable struct {
+	goTable *gosym.Table
+
+	// elfSyms is sorted by Value for binary-search lookup.
+	elfSyms []elf.Symbol
+}
+
+// loadModuleTable opens path's ELF file and builds whichever symbol tables
+// it has available.
+func loadModuleTable(path string) (*moduleTable, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t := &moduleTable{}
+
+	if goTable, err := loadGoTable(f); err == nil {
+		t.goTable = goTable
+	}
+
+	if syms, err := f.Symbols(); err == nil {
+		sort.Slice(syms, func(i, j int) bool { return syms[i].Value < syms[j].Value })
+		t.elfSyms = syms
+	}
+
+	if t.goTable == nil && len(t.elfSyms) == 0 {
+		return nil, fmt.Errorf("no symbol table found in %s", path)
+	}
+	return t, nil
+}
+
+// loadGoTable builds a gosym.Table from a Go binary's pclntab, the layout
+// Go toolchains use instead of a classic symtab since the symbol table
+// section itself is typically stripped of line info.
+func loadGoTable(f *elf.File) (*gosym.Table, error) {
+	pclntab := f.Section(".gopclntab")
+	if pclntab == nil {
+		return nil, fmt.Errorf("no .gopclntab section")
+	}
+	pclntabData, err := pclntab.Data()
+	if err != nil {
+		return nil, err
+	}
+
+	textStart := uint64(0)
+	if textSym, err := findSymbol(f, "runtime.text"); err == nil {
+		textStart = textSym.Value
+	}
+
+	lineTable := gosym.NewLineTable(pclntabData, textStart)
+
+	var symtabData []byte
+	if symtab := f.Section(".gosymtab"); symtab != nil {
+		symtabData, _ = symtab.Data()
+	}
+
+	return gosym.NewTable(symtabData, lineTable)
+}
+
+func findSymbol(f *elf.File, name string) (elf.Symbol, error) {
+	syms, err := f.Symbols()
+	if err != nil {
+		return elf.Symbol{}, err
+	}
+	for _, s := range syms {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return elf.Symbol{}, fmt.Errorf("symbol %s not found", name)
+}
+
+// lookup resolves fileOffset to a function name and that function's
+// starting file offset, preferring the Go table when present.
+func (t *moduleTable) lookup(fileOffset uint64) (name string, base uint64, ok bool) {
+	if t.goTable != nil {
+		if fn := t.goTable.PCToFunc(fileOffset); fn != nil {
+			return fn.Name, uint64(fn.Entry), true
+		}
+	}
+
+	i := sort.Search(len(t.elfSyms), func(i int) bool { return t.elfSyms[i].Value > fileOffset }) - 1
+	if i < 0 || i >= len(t.elfSyms) {
+		return "", 0, false
+	}
+	sym := t.elfSyms[i]
+	if sym.Size != 0 && fileOffset >= sym.Value+sym.Size {
+		return "", 0, false
+	}
+	return sym.Name, sym.Value, true
+}
@@ -0,0 +1,206 @@
+// Package symbolize resolves user-space stack trace addresses captured by
+// the eBPF program into module+function+offset frames, the same technique
+// Beyla-style tracers use to attach readable stacks to kernel-captured
+// events. Symbol tables are loaded lazily per process and cached until the
+// process exits.
+package symbolize
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Frame is one resolved stack frame.
+type Frame struct {
+	Address  uint64 `json:"address"`
+	Module   string `json:"module"`
+	Function string `json:"function,omitempty"`
+	Offset   uint64 `json:"offset"`
+}
+
+// String renders a frame as "module+function+0xoffset", or just the
+// address if the module couldn't be identified.
+func (f Frame) String() string {
+	if f.Module == "" {
+		return fmt.Sprintf("0x%x", f.Address)
+	}
+	if f.Function == "" {
+		return fmt.Sprintf("%s+0x%x", f.Module, f.Offset)
+	}
+	return fmt.Sprintf("%s+%s+0x%x", f.Module, f.Function, f.Offset)
+}
+
+// mapping is one executable region of a process's address space, parsed
+// from /proc/<pid>/maps.
+type mapping struct {
+	start, end uint64
+	fileOffset uint64
+	path       string
+}
+
+// procSymbols caches a process's memory mappings and the module tables
+// resolved from them.
+type procSymbols struct {
+	mappings []mapping
+	modules  map[string]*moduleTable // path -> loaded table, nil if load failed
+}
+
+// Symbolizer resolves stack addresses to frames, caching per-PID state
+// until invalidated (normally on process exit).
+type Symbolizer struct {
+	mu    sync.Mutex
+	procs map[uint32]*procSymbols
+}
+
+// New returns an empty Symbolizer.
+func New() *Symbolizer {
+	return &Symbolizer{procs: make(map[uint32]*procSymbols)}
+}
+
+// Symbolize resolves each address in ips (as captured by bpf_get_stackid)
+// to a Frame, loading and caching pid's symbol tables on first sight.
+func (s *Symbolizer) Symbolize(pid uint32, ips []uint64) []Frame {
+	ps := s.procFor(pid)
+
+	frames := make([]Frame, 0, len(ips))
+	for _, ip := range ips {
+		if ip == 0 {
+			continue
+		}
+		frames = append(frames, ps.resolve(ip))
+	}
+	return frames
+}
+
+// Invalidate discards cached symbol state for pid. Call this when pid's
+// process exits, since its mappings and any tables loaded from its binary
+// are no longer useful (and a reused PID would otherwise resolve against a
+// stale binary).
+func (s *Symbolizer) Invalidate(pid uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.procs, pid)
+}
+
+func (s *Symbolizer) procFor(pid uint32) *procSymbols {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ps, ok := s.procs[pid]; ok {
+		return ps
+	}
+
+	ps := &procSymbols{modules: make(map[string]*moduleTable)}
+	ps.mappings, _ = loadMappings(pid)
+	s.procs[pid] = ps
+	return ps
+}
+
+func (ps *procSymbols) resolve(ip uint64) Frame {
+	m, ok := ps.mappingFor(ip)
+	if !ok {
+		return Frame{Address: ip}
+	}
+
+	table := ps.tableFor(m.path)
+	fileOffset := (ip - m.start) + m.fileOffset
+
+	frame := Frame{Address: ip, Module: m.path, Offset: fileOffset}
+	if table != nil {
+		if name, base, ok := table.lookup(fileOffset); ok {
+			frame.Function = name
+			frame.Offset = fileOffset - base
+		}
+	}
+	return frame
+}
+
+func (ps *procSymbols) mappingFor(ip uint64) (mapping, bool) {
+	for _, m := range ps.mappings {
+		if ip >= m.start && ip < m.end {
+			return m, true
+		}
+	}
+	return mapping{}, false
+}
+
+func (ps *procSymbols) tableFor(path string) *moduleTable {
+	if t, ok := ps.modules[path]; ok {
+		return t
+	}
+
+	table, err := loadModuleTable(path)
+	if err != nil {
+		ps.modules[path] = nil
+		return nil
+	}
+	ps.modules[path] = table
+	return table
+}
+
+// loadMappings parses /proc/<pid>/maps for executable file-backed regions,
+// which is all a user-stack frame can point into.
+func loadMappings(pid uint32) ([]mapping, error) {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/maps", pid))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mappings []mapping
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		m, ok := parseMapsLine(scanner.Text())
+		if ok {
+			mappings = append(mappings, m)
+		}
+	}
+
+	sort.Slice(mappings, func(i, j int) bool { return mappings[i].start < mappings[j].start })
+	return mappings, scanner.Err()
+}
+
+// parseMapsLine parses one /proc/<pid>/maps line, keeping only executable,
+// file-backed mappings:
+//
+//	555555554000-555555576000 r-xp 00000000 00:1f 123  /path/to/binary
+func parseMapsLine(line string) (mapping, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 6 {
+		return mapping{}, false
+	}
+
+	addrs, perms, offsetField, path := fields[0], fields[1], fields[2], fields[5]
+
+	if len(perms) < 3 || perms[2] != 'x' {
+		return mapping{}, false
+	}
+	if !strings.HasPrefix(path, "/") {
+		return mapping{}, false
+	}
+
+	start, end, ok := strings.Cut(addrs, "-")
+	if !ok {
+		return mapping{}, false
+	}
+
+	startAddr, err := strconv.ParseUint(start, 16, 64)
+	if err != nil {
+		return mapping{}, false
+	}
+	endAddr, err := strconv.ParseUint(end, 16, 64)
+	if err != nil {
+		return mapping{}, false
+	}
+	offset, err := strconv.ParseUint(offsetField, 16, 64)
+	if err != nil {
+		return mapping{}, false
+	}
+
+	return mapping{start: startAddr, end: endAddr, fileOffset: offset, path: path}, true
+}